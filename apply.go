@@ -0,0 +1,109 @@
+// Copyright 2015 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package flagz
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	flag "github.com/spf13/pflag"
+)
+
+// transactionalValue is implemented by every `Dyn*` flag value in this package. It lets
+// ApplyJSON prepare a new value for a flag — parsing and validating it — without yet making it
+// visible to readers, so that a whole batch of flags can be validated before any of them change.
+type transactionalValue interface {
+	flag.Value
+	prepareSet(input string) (commit func(), err error)
+}
+
+// ApplyJSON applies a batch of dynamic flag values from a single JSON document shaped like
+// `{"flag-a": {...}, "flag-b": 42}`, atomically: every entry is first parsed and validated
+// against its target flag without mutating any state, and only if every entry passes are they all
+// committed. If any entry is unknown, not dynamic, or fails validation, ApplyJSON returns an error
+// describing every failure and leaves every flag untouched.
+//
+// This is useful when two or more flags must change together to remain consistent — e.g. a
+// feature-gate percentage and the backend URL it routes to — which a series of independent `Set`
+// calls cannot guarantee.
+func ApplyJSON(fs *flag.FlagSet, doc []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(doc, &raw); err != nil {
+		return fmt.Errorf("flagz: ApplyJSON: malformed document: %v", err)
+	}
+
+	type prepared struct {
+		name   string
+		commit func()
+	}
+	var commits []prepared
+	var errs []string
+	for name, value := range raw {
+		f := fs.Lookup(name)
+		if f == nil {
+			errs = append(errs, fmt.Sprintf("%s: no such flag", name))
+			continue
+		}
+		if !IsFlagDynamic(f) {
+			errs = append(errs, fmt.Sprintf("%s: not a dynamic flag", name))
+			continue
+		}
+		tv, ok := f.Value.(transactionalValue)
+		if !ok {
+			// Every Dyn* type in this package implements transactionalValue; this only fires
+			// for a caller-supplied dynamic flag.Value that doesn't, so flag it distinctly
+			// from the "not a dynamic flag" case DumpJSON would itself skip.
+			errs = append(errs, fmt.Sprintf("%s: dynamic flag does not support transactional apply", name))
+			continue
+		}
+		commit, err := tv.prepareSet(string(value))
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+		commits = append(commits, prepared{name: name, commit: commit})
+	}
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return fmt.Errorf("flagz: ApplyJSON: %s", strings.Join(errs, "; "))
+	}
+	for _, c := range commits {
+		c.commit()
+	}
+	return nil
+}
+
+// DumpJSON emits the current values of every dynamic flag in `fs` as a single JSON document
+// shaped like `{"flag-a": {...}, "flag-b": 42}`, suitable for feeding back into ApplyJSON.
+//
+// Most Dyn* types' `String()` already returns JSON (DynJSONValue, DynProtoValue, a DynValue[T]
+// using jsonCodec/protoCodec/textCodec). Any other dynamic flag.Value whose `String()` is not
+// valid JSON has its value JSON-string-encoded instead, so the document as a whole is always
+// valid JSON.
+func DumpJSON(fs *flag.FlagSet) ([]byte, error) {
+	raw := map[string]json.RawMessage{}
+	var marshalErr error
+	fs.VisitAll(func(f *flag.Flag) {
+		if marshalErr != nil || !IsFlagDynamic(f) {
+			return
+		}
+		s := f.Value.String()
+		if json.Valid([]byte(s)) {
+			raw[f.Name] = json.RawMessage(s)
+			return
+		}
+		encoded, err := json.Marshal(s)
+		if err != nil {
+			marshalErr = fmt.Errorf("flagz: DumpJSON: %s: %v", f.Name, err)
+			return
+		}
+		raw[f.Name] = json.RawMessage(encoded)
+	})
+	if marshalErr != nil {
+		return nil, marshalErr
+	}
+	return json.Marshal(raw)
+}
@@ -0,0 +1,119 @@
+// Copyright 2015 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package flagz
+
+import (
+	"encoding/json"
+	"testing"
+
+	flag "github.com/spf13/pflag"
+)
+
+func TestApplyJSON_AppliesAllOnSuccess(t *testing.T) {
+	flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+	a := DynJSONOf(flagSet, "a", 0, "usage")
+	b := DynStringOf(flagSet, "b", "", "usage")
+
+	doc := []byte(`{"a":1,"b":"hi"}`)
+	if err := ApplyJSON(flagSet, doc); err != nil {
+		t.Fatalf("ApplyJSON: %v", err)
+	}
+	if got := a.Get(); got != 1 {
+		t.Errorf("a = %d, want 1", got)
+	}
+	if got := b.Get(); got != "hi" {
+		t.Errorf("b = %q, want %q", got, "hi")
+	}
+}
+
+func TestApplyJSON_RollsBackAllOnAnyFailure(t *testing.T) {
+	flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+	a := DynJSONOf(flagSet, "a", 1, "usage")
+	b := DynJSONOf(flagSet, "b", 2, "usage")
+	b.WithValidator(func(v int) error {
+		if v > 100 {
+			return errValidationFailed
+		}
+		return nil
+	})
+
+	// "a" is individually valid, but "b" fails validation — neither should be applied.
+	doc := []byte(`{"a":99,"b":999}`)
+	if err := ApplyJSON(flagSet, doc); err == nil {
+		t.Fatal("expected ApplyJSON to fail")
+	}
+	if got := a.Get(); got != 1 {
+		t.Errorf("a should be untouched after a failed ApplyJSON, got %d", got)
+	}
+	if got := b.Get(); got != 2 {
+		t.Errorf("b should be untouched after a failed ApplyJSON, got %d", got)
+	}
+}
+
+func TestApplyJSON_UnknownFlagIsReported(t *testing.T) {
+	flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+	DynJSONOf(flagSet, "a", 1, "usage")
+
+	if err := ApplyJSON(flagSet, []byte(`{"does-not-exist":1}`)); err == nil {
+		t.Fatal("expected ApplyJSON to fail for an unknown flag")
+	}
+}
+
+func TestApplyJSON_NonDynamicFlagIsReported(t *testing.T) {
+	flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+	flagSet.String("static", "x", "usage")
+
+	if err := ApplyJSON(flagSet, []byte(`{"static":"y"}`)); err == nil {
+		t.Fatal("expected ApplyJSON to fail for a non-dynamic flag")
+	}
+	if got := flagSet.Lookup("static").Value.String(); got != "x" {
+		t.Errorf("static should be untouched, got %q", got)
+	}
+}
+
+func TestDumpJSON_ThenApplyJSONRoundTrips(t *testing.T) {
+	flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+	DynJSONOf(flagSet, "count", 5, "usage")
+	DynSliceOf(flagSet, "hosts", []string{"a", "b"}, "usage")
+	// A non-JSON-shaped flag.String() (a plain pflag.String, not one of our Dyn* types) is never
+	// emitted by DumpJSON because it isn't dynamic; only a dynamic flag's value is included.
+	flagSet.String("static", "unchanged", "usage")
+
+	dump, err := DumpJSON(flagSet)
+	if err != nil {
+		t.Fatalf("DumpJSON: %v", err)
+	}
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(dump, &doc); err != nil {
+		t.Fatalf("DumpJSON produced invalid JSON: %v", err)
+	}
+	if _, ok := doc["static"]; ok {
+		t.Error("DumpJSON should not include the non-dynamic static flag")
+	}
+
+	if err := ApplyJSON(flagSet, dump); err != nil {
+		t.Fatalf("ApplyJSON(DumpJSON(fs)) should round-trip cleanly: %v", err)
+	}
+}
+
+func TestDumpJSON_EncodesStringCodecValues(t *testing.T) {
+	flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+	DynStringOf(flagSet, "greeting", "hi there", "usage")
+
+	dump, err := DumpJSON(flagSet)
+	if err != nil {
+		t.Fatalf("DumpJSON: %v", err)
+	}
+	if !json.Valid(dump) {
+		t.Fatalf("DumpJSON produced invalid JSON: %s", dump)
+	}
+
+	var doc map[string]string
+	if err := json.Unmarshal(dump, &doc); err != nil {
+		t.Fatalf("dumped document did not decode as {flag: string}: %v", err)
+	}
+	if doc["greeting"] != "hi there" {
+		t.Errorf("greeting = %q, want %q", doc["greeting"], "hi there")
+	}
+}
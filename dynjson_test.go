@@ -0,0 +1,102 @@
+// Copyright 2015 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package flagz
+
+import (
+	"encoding/json"
+	"testing"
+
+	flag "github.com/spf13/pflag"
+)
+
+type dynJSONTestStruct struct {
+	Color string `json:"color"`
+}
+
+func TestDynJSON_SetGetRoundTrip(t *testing.T) {
+	flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+	dyn := DynJSON(flagSet, "config", &dynJSONTestStruct{Color: "red"}, "usage")
+
+	if err := flagSet.Set("config", `{"color":"blue"}`); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if got := dyn.Get().(*dynJSONTestStruct).Color; got != "blue" {
+		t.Errorf("Color = %q, want %q", got, "blue")
+	}
+}
+
+func TestDynJSON_PanicsOnNonPointerToStruct(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected DynJSON to panic on a non-pointer-to-struct value")
+		}
+	}()
+	flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+	DynJSON(flagSet, "config", dynJSONTestStruct{}, "usage")
+}
+
+func TestDynJSON_DefaultAllowsUnknownFields(t *testing.T) {
+	flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+	dyn := DynJSON(flagSet, "config", &dynJSONTestStruct{}, "usage")
+
+	if err := dyn.Set(`{"color":"blue","typo":"oops"}`); err != nil {
+		t.Errorf("default decode should ignore unknown fields, got error: %v", err)
+	}
+}
+
+func TestDynJSON_WithDisallowUnknownFieldsRejectsTypos(t *testing.T) {
+	flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+	dyn := DynJSON(flagSet, "config", &dynJSONTestStruct{}, "usage")
+	dyn.WithDisallowUnknownFields()
+
+	if err := dyn.Set(`{"color":"blue","typo":"oops"}`); err == nil {
+		t.Error("expected an unknown field to be rejected")
+	}
+	if err := dyn.Set(`{"color":"green"}`); err != nil {
+		t.Errorf("a value with only known fields should still be accepted, got: %v", err)
+	}
+}
+
+func TestDynJSON_WithDisallowUnknownFieldsRejectsTrailingData(t *testing.T) {
+	flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+	dyn := DynJSON(flagSet, "config", &dynJSONTestStruct{}, "usage")
+	dyn.WithDisallowUnknownFields()
+
+	// json.Decoder.Decode stops after the first JSON value and ignores what follows unless the
+	// caller checks for it; this must be rejected so the strict option isn't laxer than
+	// the default json.Unmarshal path, which does reject trailing data.
+	if err := dyn.Set(`{"color":"blue"}{"color":"green"}`); err == nil {
+		t.Error("expected trailing data after the JSON value to be rejected")
+	}
+}
+
+func TestDynJSON_DefaultRejectsTrailingData(t *testing.T) {
+	flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+	dyn := DynJSON(flagSet, "config", &dynJSONTestStruct{}, "usage")
+
+	if err := dyn.Set(`{"color":"blue"}{"color":"green"}`); err == nil {
+		t.Error("expected the default json.Unmarshal path to reject trailing data")
+	}
+}
+
+type dynJSONNumberTestStruct struct {
+	Value interface{} `json:"value"`
+}
+
+func TestDynJSON_WithUseNumberAvoidsFloat64(t *testing.T) {
+	flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+	dyn := DynJSON(flagSet, "config", &dynJSONNumberTestStruct{}, "usage")
+	dyn.WithUseNumber()
+
+	if err := dyn.Set(`{"value":9007199254740993}`); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	num, ok := dyn.Get().(*dynJSONNumberTestStruct).Value.(json.Number)
+	if !ok {
+		t.Fatalf("Value = %T, want json.Number", dyn.Get().(*dynJSONNumberTestStruct).Value)
+	}
+	if num.String() != "9007199254740993" {
+		t.Errorf("Value = %s, want the precise integer unmodified by float64 rounding", num.String())
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2015 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package flagz
+
+import (
+	"sync/atomic"
+
+	flag "github.com/spf13/pflag"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// DynProto creates a `Flag` that is backed by an arbitrary proto.Message, encoded as JSON on the
+// wire via `protojson`. This allows services to hot-reload structured config defined in `.proto`
+// files (e.g. bootstrap/xDS-style configs), honoring proto3 semantics (unknown fields, well-known
+// types such as `Duration`/`Timestamp`, `oneof`) rather than plain `encoding/json`.
+// `msg` is used only to determine the concrete message type; new instances of that type are
+// created on each update.
+func DynProto(flagSet *flag.FlagSet, name string, msg proto.Message, usage string) *DynProtoValue {
+	msgType := msg.ProtoReflect().Type()
+	dynValue := &DynProtoValue{msgType: msgType}
+	dynValue.ptr.Store(&msg)
+	flag := flagSet.VarPF(dynValue, name, "", usage)
+	setFlagDynamic(flag)
+	return dynValue
+}
+
+// DynProtoValue is a flag-related proto.Message value wrapper.
+type DynProtoValue struct {
+	msgType   protoreflect.MessageType
+	ptr       atomic.Pointer[proto.Message]
+	validator func(proto.Message) error
+	notifier  func(oldValue proto.Message, newValue proto.Message)
+}
+
+// Get retrieves the value in its original proto.Message type in a thread-safe manner.
+func (d *DynProtoValue) Get() proto.Message {
+	return *d.ptr.Load()
+}
+
+// GetAs is a typed helper that retrieves the value already asserted to concrete type `T`, saving
+// callers the type assertion that `Get` would otherwise require.
+func GetAs[T proto.Message](d *DynProtoValue) T {
+	return d.Get().(T)
+}
+
+// Set updates the value from its `protojson` string representation in a thread-safe manner.
+// This operation may return an error if the provided `input` doesn't parse, or the resulting value
+// doesn't pass an optional validator.
+// If a notifier is set on the value, it will be invoked in a separate go-routine.
+func (d *DynProtoValue) Set(input string) error {
+	commit, err := d.prepareSet(input)
+	if err != nil {
+		return err
+	}
+	commit()
+	return nil
+}
+
+// prepareSet parses and validates `input`, but defers making it visible to readers until the
+// returned `commit` is called. This lets ApplyJSON validate a whole batch of flags before
+// mutating any of them.
+func (d *DynProtoValue) prepareSet(input string) (commit func(), err error) {
+	newMsg := d.msgType.New().Interface()
+	if err := protojson.UnmarshalOptions{DiscardUnknown: false}.Unmarshal([]byte(input), newMsg); err != nil {
+		return nil, err
+	}
+	if d.validator != nil {
+		if err := d.validator(newMsg); err != nil {
+			return nil, err
+		}
+	}
+	return func() {
+		oldMsg := d.ptr.Swap(&newMsg)
+		if d.notifier != nil {
+			go d.notifier(*oldMsg, newMsg)
+		}
+	}, nil
+}
+
+// WithValidator adds a function that checks values before they're set.
+// Any error returned by the validator will lead to the value being rejected.
+// Validators are executed on the same go-routine as the call to `Set`.
+func (d *DynProtoValue) WithValidator(validator func(proto.Message) error) {
+	d.validator = validator
+}
+
+// WithNotifier adds a function is called every time a new value is successfully set.
+// Each notifier is executed in a new go-routine.
+func (d *DynProtoValue) WithNotifier(notifier func(oldValue proto.Message, newValue proto.Message)) {
+	d.notifier = notifier
+}
+
+// Type is an indicator of what this flag represents.
+func (d *DynProtoValue) Type() string {
+	return "dyn_proto"
+}
+
+// PrettyString returns a nicely structured representation of the type.
+// In this case it returns a pretty-printed (multi-line, indented) `protojson` encoding.
+func (d *DynProtoValue) PrettyString() string {
+	out, err := protojson.MarshalOptions{Multiline: true, Indent: "  "}.Marshal(d.Get())
+	if err != nil {
+		return "ERR"
+	}
+	return string(out)
+}
+
+// String returns the canonical string representation of the type.
+func (d *DynProtoValue) String() string {
+	out, err := protojson.Marshal(d.Get())
+	if err != nil {
+		return "ERR"
+	}
+	return string(out)
+}
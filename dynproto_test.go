@@ -0,0 +1,83 @@
+// Copyright 2015 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package flagz
+
+import (
+	"errors"
+	"testing"
+
+	flag "github.com/spf13/pflag"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+var errValidationFailed = errors.New("value not allowed")
+
+func TestDynProto_SetGetRoundTrip(t *testing.T) {
+	flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+	dyn := DynProto(flagSet, "greeting", &wrapperspb.StringValue{Value: "hello"}, "usage")
+
+	if got := GetAs[*wrapperspb.StringValue](dyn).Value; got != "hello" {
+		t.Fatalf("initial value = %q, want %q", got, "hello")
+	}
+
+	if err := flagSet.Set("greeting", `"goodbye"`); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if got := GetAs[*wrapperspb.StringValue](dyn).Value; got != "goodbye" {
+		t.Errorf("after Set, value = %q, want %q", got, "goodbye")
+	}
+	// The well-known wrapper type round-trips through protojson as the bare scalar, not
+	// `{"value": ...}` — this is the proto3 well-known-type handling the request asked for.
+	if got := dyn.String(); got != `"goodbye"` {
+		t.Errorf("String() = %s, want %s", got, `"goodbye"`)
+	}
+}
+
+func TestDynProto_SetRejectsMalformedInput(t *testing.T) {
+	flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+	dyn := DynProto(flagSet, "greeting", &wrapperspb.StringValue{}, "usage")
+
+	if err := dyn.Set("not json"); err == nil {
+		t.Error("expected Set with malformed protojson to fail")
+	}
+}
+
+func TestDynProto_ValidatorRejectsValue(t *testing.T) {
+	flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+	dyn := DynProto(flagSet, "greeting", &wrapperspb.StringValue{Value: "ok"}, "usage")
+	dyn.WithValidator(func(msg proto.Message) error {
+		if msg.(*wrapperspb.StringValue).Value == "forbidden" {
+			return errValidationFailed
+		}
+		return nil
+	})
+
+	if err := dyn.Set(`"forbidden"`); err == nil {
+		t.Error("expected validator to reject the value")
+	}
+	if got := GetAs[*wrapperspb.StringValue](dyn).Value; got != "ok" {
+		t.Errorf("rejected Set should not mutate the value, got %q", got)
+	}
+}
+
+func TestDynProto_NotifierFiresWithOldAndNewValues(t *testing.T) {
+	flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+	dyn := DynProto(flagSet, "greeting", &wrapperspb.StringValue{Value: "a"}, "usage")
+
+	notified := make(chan [2]string, 1)
+	dyn.WithNotifier(func(oldValue, newValue proto.Message) {
+		notified <- [2]string{
+			oldValue.(*wrapperspb.StringValue).Value,
+			newValue.(*wrapperspb.StringValue).Value,
+		}
+	})
+	if err := dyn.Set(`"b"`); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	got := <-notified
+	if got[0] != "a" || got[1] != "b" {
+		t.Errorf("notifier saw (old=%q, new=%q), want (a, b)", got[0], got[1])
+	}
+}
@@ -0,0 +1,205 @@
+// Copyright 2015 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package flagz
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync/atomic"
+
+	flag "github.com/spf13/pflag"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Codec controls how a DynValue's in-memory representation is converted to and from the string
+// that `Set` receives and `String` returns. Built-in codecs cover JSON (jsonCodec), protobuf JSON
+// (protoCodec) and plain strings (textCodec). Every exported constructor in this package uses one
+// of these built-in codecs; none of them accepts a caller-supplied Codec.
+type Codec[T any] interface {
+	Marshal(value T) ([]byte, error)
+	Unmarshal(data []byte) (T, error)
+}
+
+// DynValue is a generic dynamic flag value: a type-safe replacement for the older reflect-based
+// `Dyn*` types (e.g. DynJSONValue) that requires no type assertions at the call site.
+// New values are swapped in atomically, so `Get` never blocks a concurrent `Set`.
+type DynValue[T any] struct {
+	ptr       atomic.Pointer[T]
+	codec     Codec[T]
+	validator func(T) error
+	notifier  func(oldValue T, newValue T)
+}
+
+func newDynValue[T any](flagSet *flag.FlagSet, name string, def T, codec Codec[T], usage string) *DynValue[T] {
+	d := &DynValue[T]{codec: codec}
+	d.ptr.Store(&def)
+	flag := flagSet.VarPF(d, name, "", usage)
+	setFlagDynamic(flag)
+	return d
+}
+
+// Get retrieves the value in a thread-safe manner.
+func (d *DynValue[T]) Get() T {
+	return *d.ptr.Load()
+}
+
+// Set updates the value from its wire representation, as produced by the value's Codec, in a
+// thread-safe manner. This operation may return an error if `input` doesn't decode, or the
+// resulting value doesn't pass an optional validator.
+// If a notifier is set on the value, it will be invoked in a separate go-routine.
+func (d *DynValue[T]) Set(input string) error {
+	commit, err := d.prepareSet(input)
+	if err != nil {
+		return err
+	}
+	commit()
+	return nil
+}
+
+// prepareSet decodes and validates `input`, but defers making it visible to readers until the
+// returned `commit` is called. This lets ApplyJSON validate a whole batch of flags before
+// mutating any of them.
+func (d *DynValue[T]) prepareSet(input string) (commit func(), err error) {
+	newValue, err := d.codec.Unmarshal([]byte(input))
+	if err != nil {
+		return nil, err
+	}
+	if d.validator != nil {
+		if err := d.validator(newValue); err != nil {
+			return nil, err
+		}
+	}
+	return func() {
+		oldValue := d.ptr.Swap(&newValue)
+		if d.notifier != nil {
+			go d.notifier(*oldValue, newValue)
+		}
+	}, nil
+}
+
+// WithValidator adds a function that checks values before they're set.
+// Any error returned by the validator will lead to the value being rejected.
+// Validators are executed on the same go-routine as the call to `Set`. Returns the receiver so it
+// can be chained onto the constructor call.
+func (d *DynValue[T]) WithValidator(validator func(T) error) *DynValue[T] {
+	d.validator = validator
+	return d
+}
+
+// WithNotifier adds a function that is called every time a new value is successfully set.
+// Each notifier is executed in a new go-routine. Returns the receiver so it can be chained onto
+// the constructor call.
+func (d *DynValue[T]) WithNotifier(notifier func(oldValue T, newValue T)) *DynValue[T] {
+	d.notifier = notifier
+	return d
+}
+
+// Type is an indicator of what this flag represents.
+func (d *DynValue[T]) Type() string {
+	return "dyn_value"
+}
+
+// String returns the canonical string representation of the type, as produced by its Codec.
+func (d *DynValue[T]) String() string {
+	out, err := d.codec.Marshal(d.Get())
+	if err != nil {
+		return "ERR"
+	}
+	return string(out)
+}
+
+// PrettyString returns a nicely structured representation of the type. For JSON-shaped codecs
+// (jsonCodec, protoCodec) this is multi-line indented JSON; other codecs fall back to String.
+func (d *DynValue[T]) PrettyString() string {
+	raw, err := d.codec.Marshal(d.Get())
+	if err != nil {
+		return "ERR"
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, raw, "", "  "); err != nil {
+		return string(raw)
+	}
+	return buf.String()
+}
+
+// jsonCodec (de)serializes a value using `encoding/json`.
+type jsonCodec[T any] struct{}
+
+func (jsonCodec[T]) Marshal(value T) ([]byte, error) { return json.Marshal(value) }
+func (jsonCodec[T]) Unmarshal(data []byte) (T, error) {
+	var value T
+	err := json.Unmarshal(data, &value)
+	return value, err
+}
+
+// textCodec is for plain string flags: `Set` accepts the bare string as-is (e.g. `--greeting=hello`
+// or the chunk0-2 admin endpoint's `value=hello` both just work), which is what callers of a
+// string flag expect. `Marshal` still emits a JSON string literal, so `String()`/`DumpJSON` stay
+// valid JSON documents; `Unmarshal` correspondingly accepts that JSON-string-literal form too —
+// trying it first — so a value round-tripped through DumpJSON/ApplyJSON decodes back to the same
+// string rather than picking up stray quote characters.
+//
+// The one case this can't disambiguate is a bare value that happens to itself be a valid
+// JSON-quoted string, e.g. Set(`"hello"`): it is read back as hello, quotes and all stripped,
+// rather than literally `"hello"`. That trade-off is preferable to requiring every caller of a
+// plain string flag to quote it.
+type textCodec struct{}
+
+func (textCodec) Marshal(value string) ([]byte, error) { return json.Marshal(value) }
+func (textCodec) Unmarshal(data []byte) (string, error) {
+	var value string
+	if err := json.Unmarshal(data, &value); err == nil {
+		return value, nil
+	}
+	return string(data), nil
+}
+
+// protoCodec (de)serializes a proto.Message using `protojson`.
+type protoCodec[T proto.Message] struct {
+	msgType protoreflect.MessageType
+}
+
+func (c protoCodec[T]) Marshal(value T) ([]byte, error) { return protojson.Marshal(value) }
+func (c protoCodec[T]) Unmarshal(data []byte) (T, error) {
+	msg := c.msgType.New().Interface().(T)
+	if err := protojson.Unmarshal(data, msg); err != nil {
+		var zero T
+		return zero, err
+	}
+	return msg, nil
+}
+
+// DynJSONOf creates a DynValue[T] backed by `encoding/json`. Unlike the older `DynJSON`, `T` can
+// be any JSON-marshallable type (not just a struct pointer) and `Get` returns it with no type
+// assertion required.
+func DynJSONOf[T any](flagSet *flag.FlagSet, name string, def T, usage string) *DynValue[T] {
+	return newDynValue(flagSet, name, def, jsonCodec[T]{}, usage)
+}
+
+// DynSliceOf creates a DynValue[[]T] backed by `encoding/json`, for flags whose value is a list.
+func DynSliceOf[T any](flagSet *flag.FlagSet, name string, def []T, usage string) *DynValue[[]T] {
+	return newDynValue(flagSet, name, def, jsonCodec[[]T]{}, usage)
+}
+
+// DynSetOf creates a DynValue[map[T]struct{}] backed by `encoding/json` (encoded as a JSON object
+// whose keys are the set members), for flags whose value is an unordered set of comparable
+// elements.
+func DynSetOf[T comparable](flagSet *flag.FlagSet, name string, def map[T]struct{}, usage string) *DynValue[map[T]struct{}] {
+	return newDynValue(flagSet, name, def, jsonCodec[map[T]struct{}]{}, usage)
+}
+
+// DynProtoOf creates a DynValue[T] backed by `protojson`, for a concrete generated proto.Message
+// type T. It is the generic counterpart of DynProto.
+func DynProtoOf[T proto.Message](flagSet *flag.FlagSet, name string, def T, usage string) *DynValue[T] {
+	return newDynValue(flagSet, name, def, protoCodec[T]{msgType: def.ProtoReflect().Type()}, usage)
+}
+
+// DynStringOf creates a DynValue[string] for a plain string flag. `Set` accepts the bare string
+// (e.g. `--greeting=hello`); `String()`/`DumpJSON` emit it as a JSON string literal so it still
+// round-trips through ApplyJSON.
+func DynStringOf(flagSet *flag.FlagSet, name string, def string, usage string) *DynValue[string] {
+	return newDynValue(flagSet, name, def, textCodec{}, usage)
+}
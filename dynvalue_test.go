@@ -0,0 +1,138 @@
+// Copyright 2015 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package flagz
+
+import (
+	"testing"
+
+	flag "github.com/spf13/pflag"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type dynValueTestStruct struct {
+	Color string `json:"color"`
+}
+
+func TestDynJSONOf_SetGet(t *testing.T) {
+	flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+	dyn := DynJSONOf(flagSet, "config", dynValueTestStruct{Color: "red"}, "usage")
+
+	if got := dyn.Get().Color; got != "red" {
+		t.Fatalf("initial Color = %q, want %q", got, "red")
+	}
+	if err := dyn.Set(`{"color":"blue"}`); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if got := dyn.Get().Color; got != "blue" {
+		t.Errorf("Color = %q, want %q", got, "blue")
+	}
+}
+
+func TestDynSliceOf_SetGet(t *testing.T) {
+	flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+	dyn := DynSliceOf(flagSet, "hosts", []string{"a"}, "usage")
+
+	if err := dyn.Set(`["a","b","c"]`); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	got := dyn.Get()
+	want := []string{"a", "b", "c"}
+	if len(got) != len(want) {
+		t.Fatalf("Get() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Get()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestDynSetOf_SetGet(t *testing.T) {
+	flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+	dyn := DynSetOf(flagSet, "tags", map[string]struct{}{}, "usage")
+
+	if err := dyn.Set(`{"a":{},"b":{}}`); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	got := dyn.Get()
+	if _, ok := got["a"]; !ok {
+		t.Error(`expected "a" in set`)
+	}
+	if _, ok := got["b"]; !ok {
+		t.Error(`expected "b" in set`)
+	}
+	if len(got) != 2 {
+		t.Errorf("len(Get()) = %d, want 2", len(got))
+	}
+}
+
+func TestDynProtoOf_SetGet(t *testing.T) {
+	flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+	dyn := DynProtoOf(flagSet, "greeting", &wrapperspb.StringValue{Value: "hello"}, "usage")
+
+	if err := dyn.Set(`"world"`); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if got := dyn.Get().Value; got != "world" {
+		t.Errorf("Get().Value = %q, want %q", got, "world")
+	}
+}
+
+// TestDynStringOf_SetAcceptsBareString is the chunk0-2 admin endpoint / pflag `--greeting=hello`
+// path: both call Set with the bare string, not a JSON-quoted one.
+func TestDynStringOf_SetAcceptsBareString(t *testing.T) {
+	flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+	dyn := DynStringOf(flagSet, "greeting", "", "usage")
+
+	if err := flagSet.Set("greeting", "hello"); err != nil {
+		t.Fatalf("Set(bare string): %v", err)
+	}
+	if got := dyn.Get(); got != "hello" {
+		t.Errorf("Get() = %q, want %q", got, "hello")
+	}
+}
+
+// TestDynStringOf_DumpApplyRoundTrips guards against the textCodec bug where String()/DumpJSON
+// produced a JSON-quoted value that ApplyJSON then couldn't feed back through the same bare-string
+// Set contract — DumpJSON's output must ApplyJSON cleanly back to the same value.
+func TestDynStringOf_DumpApplyRoundTrips(t *testing.T) {
+	flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+	dyn := DynStringOf(flagSet, "greeting", "hello", "usage")
+
+	if err := flagSet.Set("greeting", "world"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if got := dyn.String(); got != `"world"` {
+		t.Errorf("String() = %s, want %s", got, `"world"`)
+	}
+
+	dump, err := DumpJSON(flagSet)
+	if err != nil {
+		t.Fatalf("DumpJSON: %v", err)
+	}
+	if err := ApplyJSON(flagSet, dump); err != nil {
+		t.Fatalf("ApplyJSON(DumpJSON(...)) should round-trip cleanly: %v", err)
+	}
+	if got := dyn.Get(); got != "world" {
+		t.Errorf("after round-trip, Get() = %q, want %q", got, "world")
+	}
+}
+
+func TestDynValue_ValidatorRejectsWithoutMutating(t *testing.T) {
+	flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+	dyn := DynJSONOf(flagSet, "count", 1, "usage")
+	dyn.WithValidator(func(v int) error {
+		if v < 0 {
+			return errValidationFailed
+		}
+		return nil
+	})
+
+	if err := dyn.Set("-1"); err == nil {
+		t.Error("expected validator to reject a negative value")
+	}
+	if got := dyn.Get(); got != 1 {
+		t.Errorf("rejected Set should not mutate the value, got %d", got)
+	}
+}
@@ -0,0 +1,183 @@
+// Copyright 2015 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+// Package endpoint provides an `http.Handler` for inspecting the flags of a
+// `pflag.FlagSet` and mutating its dynamic ones at runtime, so that services using
+// flagz don't need to hand-write an admin page or API for the runtime-tuning
+// workflow.
+package endpoint
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/jonboulle/go-flagz"
+	flag "github.com/spf13/pflag"
+)
+
+// Authorizer decides whether a mutating request (POST/PUT) is allowed to
+// proceed. It is not consulted for read-only (GET) requests.
+type Authorizer func(r *http.Request) bool
+
+// AllowAll is an Authorizer that permits every request. It exists mostly for
+// tests and local development; production services should supply an
+// Authorizer backed by their own authn/authz middleware.
+func AllowAll(r *http.Request) bool { return true }
+
+// Endpoint is an `http.Handler` that lists every flag of a `pflag.FlagSet`,
+// marking which are dynamic, and mutates the dynamic ones.
+type Endpoint struct {
+	flagSet    *flag.FlagSet
+	authorizer Authorizer
+}
+
+// NewEndpoint creates an Endpoint serving the flags registered on `flagSet`.
+// Mutating requests are gated behind `authorizer`; pass AllowAll
+// to permit everyone.
+func NewEndpoint(flagSet *flag.FlagSet, authorizer Authorizer) *Endpoint {
+	return &Endpoint{flagSet: flagSet, authorizer: authorizer}
+}
+
+// ServeHTTP implements http.Handler.
+func (e *Endpoint) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+		e.serveList(resp, req)
+	case http.MethodPost, http.MethodPut:
+		e.serveSet(resp, req)
+	default:
+		http.Error(resp, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+type flagInfo struct {
+	Name    string `json:"name"`
+	Usage   string `json:"usage"`
+	Dynamic bool   `json:"dynamic"`
+	Value   string `json:"value"`
+}
+
+func (e *Endpoint) collectFlags() []flagInfo {
+	var infos []flagInfo
+	e.flagSet.VisitAll(func(f *flag.Flag) {
+		infos = append(infos, flagInfo{
+			Name:    f.Name,
+			Usage:   f.Usage,
+			Dynamic: flagz.IsFlagDynamic(f),
+			Value:   prettyValue(f.Value),
+		})
+	})
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+func prettyValue(v flag.Value) string {
+	if p, ok := v.(interface{ PrettyString() string }); ok {
+		return p.PrettyString()
+	}
+	return v.String()
+}
+
+func (e *Endpoint) serveList(resp http.ResponseWriter, req *http.Request) {
+	infos := e.collectFlags()
+	if wantsJSON(req) {
+		resp.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(resp).Encode(infos); err != nil {
+			http.Error(resp, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	resp.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := listTemplate.Execute(resp, infos); err != nil {
+		http.Error(resp, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (e *Endpoint) serveSet(resp http.ResponseWriter, req *http.Request) {
+	if e.authorizer == nil || !e.authorizer(req) {
+		http.Error(resp, "forbidden", http.StatusForbidden)
+		return
+	}
+	name, value, err := parseSetRequest(req)
+	if err != nil {
+		http.Error(resp, err.Error(), http.StatusBadRequest)
+		return
+	}
+	f := e.flagSet.Lookup(name)
+	if f == nil {
+		http.Error(resp, fmt.Sprintf("no such flag: %s", name), http.StatusNotFound)
+		return
+	}
+	if !flagz.IsFlagDynamic(f) {
+		http.Error(resp, fmt.Sprintf("flag %q is not dynamic", name), http.StatusBadRequest)
+		return
+	}
+	if err := f.Value.Set(value); err != nil {
+		http.Error(resp, err.Error(), http.StatusBadRequest)
+		return
+	}
+	info := flagInfo{Name: f.Name, Usage: f.Usage, Dynamic: true, Value: prettyValue(f.Value)}
+	if wantsJSON(req) {
+		resp.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(resp).Encode(info)
+		return
+	}
+	fmt.Fprintf(resp, "%s = %s\n", info.Name, info.Value)
+}
+
+func parseSetRequest(req *http.Request) (name string, value string, err error) {
+	contentType := req.Header.Get("Content-Type")
+	if strings.Contains(contentType, "application/json") {
+		var body struct {
+			Name  string `json:"name"`
+			Value string `json:"value"`
+		}
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			return "", "", fmt.Errorf("malformed JSON body: %v", err)
+		}
+		name, value = body.Name, body.Value
+	} else {
+		if err := req.ParseForm(); err != nil {
+			return "", "", fmt.Errorf("malformed form body: %v", err)
+		}
+		name, value = req.Form.Get("name"), req.Form.Get("value")
+	}
+	if name == "" {
+		return "", "", fmt.Errorf("missing required field: name")
+	}
+	return name, value, nil
+}
+
+// wantsJSON performs simple content negotiation: callers asking for JSON
+// either via `Accept: application/json` or `?format=json` get the machine
+// view; everyone else (i.e. a browser) gets HTML.
+func wantsJSON(req *http.Request) bool {
+	if req.URL.Query().Get("format") == "json" {
+		return true
+	}
+	return strings.Contains(req.Header.Get("Accept"), "application/json")
+}
+
+var listTemplate = template.Must(template.New("flagz").Parse(`<!DOCTYPE html>
+<html>
+<head><title>flagz</title></head>
+<body>
+<h1>flagz</h1>
+<table border="1" cellpadding="4">
+<tr><th>Name</th><th>Dynamic</th><th>Value</th><th>Usage</th></tr>
+{{range .}}
+<tr>
+<td>{{.Name}}</td>
+<td>{{.Dynamic}}</td>
+<td><pre>{{.Value}}</pre></td>
+<td>{{.Usage}}</td>
+</tr>
+{{end}}
+</table>
+</body>
+</html>
+`))
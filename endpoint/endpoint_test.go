@@ -0,0 +1,113 @@
+// Copyright 2015 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package endpoint
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	flagz "github.com/jonboulle/go-flagz"
+	flag "github.com/spf13/pflag"
+)
+
+type testConfig struct {
+	Color string `json:"color"`
+}
+
+func newTestFlagSet() (*flag.FlagSet, *flagz.DynJSONValue) {
+	flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+	dyn := flagz.DynJSON(flagSet, "config", &testConfig{Color: "red"}, "usage")
+	flagSet.String("static", "unchanged", "usage")
+	return flagSet, dyn
+}
+
+func TestServeListJSON(t *testing.T) {
+	flagSet, _ := newTestFlagSet()
+	ep := NewEndpoint(flagSet, AllowAll)
+
+	req := httptest.NewRequest(http.MethodGet, "/flagz?format=json", nil)
+	resp := httptest.NewRecorder()
+	ep.ServeHTTP(resp, req)
+
+	if resp.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.Code)
+	}
+	body := resp.Body.String()
+	if !strings.Contains(body, `"name":"config"`) || !strings.Contains(body, `"dynamic":true`) {
+		t.Errorf("expected config to be listed as dynamic, got %s", body)
+	}
+	if !strings.Contains(body, `"name":"static"`) || !strings.Contains(body, `"dynamic":false`) {
+		t.Errorf("expected static to be listed as non-dynamic, got %s", body)
+	}
+}
+
+func TestServeListHTML(t *testing.T) {
+	flagSet, _ := newTestFlagSet()
+	ep := NewEndpoint(flagSet, AllowAll)
+
+	req := httptest.NewRequest(http.MethodGet, "/flagz", nil)
+	resp := httptest.NewRecorder()
+	ep.ServeHTTP(resp, req)
+
+	if ct := resp.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("Content-Type = %q, want text/html", ct)
+	}
+	if !strings.Contains(resp.Body.String(), "config") {
+		t.Errorf("expected HTML body to mention the config flag, got %s", resp.Body.String())
+	}
+}
+
+func TestServeSetAppliesNewValue(t *testing.T) {
+	flagSet, dyn := newTestFlagSet()
+	ep := NewEndpoint(flagSet, AllowAll)
+
+	form := strings.NewReader(`name=config&value=` + `{"color":"blue"}`)
+	req := httptest.NewRequest(http.MethodPost, "/flagz", form)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp := httptest.NewRecorder()
+	ep.ServeHTTP(resp, req)
+
+	if resp.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200, body: %s", resp.Code, resp.Body.String())
+	}
+	if got := dyn.Get().(*testConfig).Color; got != "blue" {
+		t.Errorf("config.Color = %q, want %q", got, "blue")
+	}
+}
+
+func TestServeSetRejectsForbidden(t *testing.T) {
+	flagSet, dyn := newTestFlagSet()
+	denyAll := func(r *http.Request) bool { return false }
+	ep := NewEndpoint(flagSet, denyAll)
+
+	form := strings.NewReader(`name=config&value=` + `{"color":"blue"}`)
+	req := httptest.NewRequest(http.MethodPost, "/flagz", form)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp := httptest.NewRecorder()
+	ep.ServeHTTP(resp, req)
+
+	if resp.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want 403", resp.Code)
+	}
+	if got := dyn.Get().(*testConfig).Color; got != "red" {
+		t.Errorf("config.Color = %q, want unchanged %q", got, "red")
+	}
+}
+
+func TestServeSetRejectsInvalidValue(t *testing.T) {
+	flagSet, _ := newTestFlagSet()
+	ep := NewEndpoint(flagSet, AllowAll)
+
+	form := strings.NewReader(`name=config&value=not-json`)
+	req := httptest.NewRequest(http.MethodPost, "/flagz", form)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp := httptest.NewRecorder()
+	ep.ServeHTTP(resp, req)
+
+	if resp.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", resp.Code)
+	}
+}
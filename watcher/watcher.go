@@ -0,0 +1,131 @@
+// Copyright 2015 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+// Package watcher watches a directory of flat files — typically a Kubernetes
+// ConfigMap volume mount — and pushes their contents into the flags of a
+// `pflag.FlagSet` whenever the directory changes. Any flag whose name matches
+// a file is set, whether or not it is marked dynamic.
+//
+// Kubernetes mounts a ConfigMap as a directory containing a symlink named
+// `..data` that points at a timestamped directory holding the real files; an
+// update swaps that symlink atomically. Rather than trying to track
+// individual file events, the watcher watches the parent directory and, on
+// any CREATE/RENAME/REMOVE of `..data` (or a plain file write, for non-
+// ConfigMap directories), re-reads every file in `dir` and calls `Set()` on
+// the flag whose name matches the file's name.
+package watcher
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/fsnotify/fsnotify"
+	flag "github.com/spf13/pflag"
+)
+
+const dataDirLink = "..data"
+
+// ReloadFunc is invoked after every reload cycle, once all files in the
+// watched directory have been processed. `errs` contains the per-flag errors
+// (if any) encountered while applying the new values; it is empty on a fully
+// successful reload.
+type ReloadFunc func(errs map[string]error)
+
+// Start watches `dir` for changes and applies them to `flagSet` until `ctx`
+// is cancelled. It performs an initial read of `dir` before watching so that
+// flags reflect the contents on disk immediately. `onReload`, if non-nil, is
+// called after the initial read and after every subsequent reload.
+//
+// Per-flag errors — an unknown file name, or a value rejected by a flag's
+// validator — are logged and recorded in the `onReload` callback, but never
+// abort the watch loop; a bad value for one flag must not prevent the rest
+// of the ConfigMap from being applied.
+func Start(ctx context.Context, flagSet *flag.FlagSet, dir string, onReload ReloadFunc) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("watcher: failed creating fsnotify watcher: %v", err)
+	}
+	if err := watcher.Add(dir); err != nil {
+		watcher.Close()
+		return fmt.Errorf("watcher: failed watching %v: %v", dir, err)
+	}
+
+	reload(flagSet, dir, onReload)
+
+	go func() {
+		defer watcher.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if !shouldReload(event) {
+					continue
+				}
+				reload(flagSet, dir, onReload)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Printf("watcher: fsnotify error watching %v: %v", dir, err)
+			}
+		}
+	}()
+	return nil
+}
+
+// shouldReload reports whether an fsnotify event should trigger a reload.
+// ConfigMap updates swap the `..data` symlink, which surfaces as a
+// CREATE/RENAME/REMOVE of that name; plain files (non-ConfigMap directories)
+// trigger a reload on WRITE.
+func shouldReload(event fsnotify.Event) bool {
+	base := filepath.Base(event.Name)
+	if base == dataDirLink {
+		return event.Op&(fsnotify.Create|fsnotify.Rename|fsnotify.Remove) != 0
+	}
+	return event.Op&fsnotify.Write != 0
+}
+
+func reload(flagSet *flag.FlagSet, dir string, onReload ReloadFunc) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		log.Printf("watcher: failed listing %v: %v", dir, err)
+		return
+	}
+	errs := map[string]error{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, "..") {
+			continue
+		}
+		if err := applyFile(flagSet, dir, name); err != nil {
+			log.Printf("watcher: failed applying flag %q from %v: %v", name, dir, err)
+			errs[name] = err
+		}
+	}
+	if onReload != nil {
+		onReload(errs)
+	}
+}
+
+func applyFile(flagSet *flag.FlagSet, dir string, name string) error {
+	f := flagSet.Lookup(name)
+	if f == nil {
+		return fmt.Errorf("no such flag")
+	}
+	content, err := ioutil.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return err
+	}
+	// kubectl edit (and most editors) leave a trailing newline; strip it so
+	// a bare value like `true` or `42` doesn't fail to parse.
+	value := strings.TrimRight(string(content), " \t\r\n")
+	return flagSet.Set(name, value)
+}
@@ -0,0 +1,79 @@
+// Copyright 2015 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package watcher
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fsnotify/fsnotify"
+	flag "github.com/spf13/pflag"
+)
+
+func TestShouldReload(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		event fsnotify.Event
+		want  bool
+	}{
+		{"configmap data dir created", fsnotify.Event{Name: "/mnt/cm/..data", Op: fsnotify.Create}, true},
+		{"configmap data dir renamed", fsnotify.Event{Name: "/mnt/cm/..data", Op: fsnotify.Rename}, true},
+		{"configmap data dir removed", fsnotify.Event{Name: "/mnt/cm/..data", Op: fsnotify.Remove}, true},
+		{"configmap data dir written", fsnotify.Event{Name: "/mnt/cm/..data", Op: fsnotify.Write}, false},
+		{"plain file written", fsnotify.Event{Name: "/mnt/cm/color", Op: fsnotify.Write}, true},
+		{"plain file created", fsnotify.Event{Name: "/mnt/cm/color", Op: fsnotify.Create}, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := shouldReload(tc.event); got != tc.want {
+				t.Errorf("shouldReload(%+v) = %v, want %v", tc.event, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestReloadAppliesFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "color"), []byte("blue\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "unknown-flag"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+	color := flagSet.String("color", "", "")
+
+	var gotErrs map[string]error
+	reload(flagSet, dir, func(errs map[string]error) { gotErrs = errs })
+
+	if *color != "blue" {
+		t.Errorf("color = %q, want %q (trailing newline should be trimmed)", *color, "blue")
+	}
+	if err, ok := gotErrs["unknown-flag"]; !ok || err == nil {
+		t.Errorf("expected an error for unknown-flag, got %v", gotErrs)
+	}
+	if _, ok := gotErrs["color"]; ok {
+		t.Errorf("color should have applied cleanly, got error %v", gotErrs["color"])
+	}
+}
+
+func TestReloadSkipsDotfilesAndDirs(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "..2024_01_01"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("..2024_01_01", filepath.Join(dir, "..data")); err != nil {
+		t.Fatal(err)
+	}
+
+	flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+	var gotErrs map[string]error
+	reload(flagSet, dir, func(errs map[string]error) { gotErrs = errs })
+
+	if len(gotErrs) != 0 {
+		t.Errorf("expected no errors from the ..data symlink or its target dir, got %v", gotErrs)
+	}
+}